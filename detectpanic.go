@@ -0,0 +1,194 @@
+package panicwrap
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"time"
+)
+
+// panicHeaders are the line prefixes (after leading tabs are stripped)
+// that mark the possible start of a panic: a normal panic, a fatal
+// runtime error such as "fatal error: all goroutines are asleep", and
+// the "runtime error:" text that prefixes many of the runtime's own
+// panic values.
+var panicHeaders = [][]byte{
+	[]byte("panic:"),
+	[]byte("fatal error:"),
+	[]byte("runtime error:"),
+}
+
+func isPanicHeader(line []byte) bool {
+	trimmed := bytes.TrimLeft(line, "\t")
+	for _, h := range panicHeaders {
+		if bytes.HasPrefix(trimmed, h) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DetectPanic reads r line by line, looking for the start of a panic.
+// Lines that don't look like the start of a panic are written to mirror
+// immediately. Once a line matching one of panicHeaders shows up,
+// DetectPanic switches to buffering instead of mirroring: it keeps
+// appending lines until either a "goroutine " line confirms the buffered
+// text really is a panic, or the suspected panic goes unconfirmed for
+// too long, at which point it's treated as a false positive and the
+// buffered lines are flushed to mirror and scanning resumes as before.
+//
+// "Too long" is governed by two independent limits, either of which
+// ends the wait: dur, the amount of time to wait after the last line was
+// buffered, and lineBudget, the number of lines to buffer before giving
+// up. A zero dur or lineBudget disables that limit. mirror defaults to
+// os.Stderr if nil.
+//
+// DetectPanic returns the confirmed panic text, or the empty string if r
+// was exhausted without one ever being confirmed.
+func DetectPanic(r io.Reader, dur time.Duration, lineBudget int, mirror io.Writer) string {
+	if mirror == nil {
+		mirror = os.Stderr
+	}
+
+	// Read in a separate goroutine so the main loop can select between
+	// new lines arriving and the detection timer expiring.
+	lineCh := make(chan []byte)
+	go func() {
+		defer close(lineCh)
+
+		reader := bufio.NewReaderSize(r, 64*1024)
+		for {
+			raw, err := readLine(reader)
+			// A line terminated by '\n' is real even if it's empty; it's
+			// only the trailing, newline-less remainder at EOF that
+			// should be dropped when empty.
+			if len(raw) > 0 || err == nil {
+				line := make([]byte, len(raw)+1)
+				copy(line, raw)
+				line[len(line)-1] = '\n'
+				lineCh <- line
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var buf bytes.Buffer
+	tracking := false
+	bufferedLines := 0
+
+	var timer *time.Timer
+	var timerCh <-chan time.Time
+	resetTimer := func() {
+		if dur <= 0 {
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.NewTimer(dur)
+		timerCh = timer.C
+	}
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timer, timerCh = nil, nil
+	}
+	falsePositive := func() {
+		mirror.Write(buf.Bytes())
+		buf.Reset()
+		tracking = false
+		bufferedLines = 0
+		stopTimer()
+	}
+
+	for {
+		select {
+		case line, ok := <-lineCh:
+			if !ok {
+				// The reader is done. If we were mid-buffer, verify what
+				// we have one last time; if it still isn't a panic, flush
+				// it to mirror like any other false positive.
+				if tracking {
+					if verifyPanic(buf.Bytes()) {
+						stopTimer()
+						return buf.String()
+					}
+					falsePositive()
+				}
+				return ""
+			}
+
+			if !tracking {
+				if !isPanicHeader(line) {
+					mirror.Write(line)
+					continue
+				}
+
+				tracking = true
+				bufferedLines = 1
+				buf.Write(line)
+				resetTimer()
+				continue
+			}
+
+			buf.Write(line)
+			bufferedLines++
+
+			if verifyPanic(buf.Bytes()) {
+				// Confirmed. Keep draining so the rest of the stack
+				// (further goroutines, etc.) makes it into the result.
+				stopTimer()
+				for more := range lineCh {
+					buf.Write(more)
+				}
+				return buf.String()
+			}
+
+			if lineBudget > 0 && bufferedLines >= lineBudget {
+				falsePositive()
+				continue
+			}
+
+			resetTimer()
+
+		case <-timerCh:
+			// Nothing new showed up within dur of the last buffered
+			// line: assume it was a false positive and flush it back
+			// out to mirror so it isn't lost.
+			falsePositive()
+		}
+	}
+}
+
+func verifyPanic(p []byte) bool {
+	return bytes.Index(p, []byte("goroutine ")) != -1
+}
+
+// readLine reads a single line from r, stripped of its trailing "\r\n" or
+// "\n", growing its read past r's internal buffer as needed so a line of
+// any length is returned whole. This is unlike bufio.Scanner, whose
+// default token size cap would make Scan return false and stop reading
+// for good the first time a single line (legitimate output can easily
+// run to 10s of MB) exceeds it.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if err != bufio.ErrBufferFull {
+			if n := len(line); n > 0 && line[n-1] == '\n' {
+				line = line[:n-1]
+				if n := len(line); n > 0 && line[n-1] == '\r' {
+					line = line[:n-1]
+				}
+			}
+			return line, err
+		}
+	}
+}
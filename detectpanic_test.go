@@ -0,0 +1,118 @@
+package panicwrap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectPanic_fatalError(t *testing.T) {
+	raw := "fatal error: all goroutines are asleep - deadlock!\n" +
+		"\n" +
+		"goroutine 1 [chan receive]:\n" +
+		"main.main()\n" +
+		"\t/tmp/main.go:8 +0x20\n"
+
+	var mirror bytes.Buffer
+	result := DetectPanic(strings.NewReader(raw), 50*time.Millisecond, 0, &mirror)
+	if result != raw {
+		t.Fatalf("bad result: %#v", result)
+	}
+
+	if mirror.Len() != 0 {
+		t.Fatalf("shouldn't have mirrored anything: %#v", mirror.String())
+	}
+}
+
+func TestDetectPanic_runtimeError(t *testing.T) {
+	raw := "panic: runtime error: index out of range [3] with length 2\n" +
+		"\n" +
+		"goroutine 1 [running]:\n" +
+		"main.main()\n" +
+		"\t/tmp/main.go:8 +0x20\n"
+
+	result := DetectPanic(strings.NewReader(raw), 50*time.Millisecond, 0, new(bytes.Buffer))
+	if result != raw {
+		t.Fatalf("bad result: %#v", result)
+	}
+}
+
+func TestDetectPanic_lineBudgetFalsePositive(t *testing.T) {
+	raw := "panic: looks scary but never confirmed\n" +
+		"just some log output\n" +
+		"more log output\n" +
+		"even more log output\n" +
+		"trailing output\n"
+
+	var mirror bytes.Buffer
+	result := DetectPanic(strings.NewReader(raw), time.Hour, 3, &mirror)
+	if result != "" {
+		t.Fatalf("shouldn't have confirmed a panic: %#v", result)
+	}
+
+	if mirror.String() != raw {
+		t.Fatalf("flushed text doesn't match input: %#v", mirror.String())
+	}
+}
+
+func TestDetectPanic_lineOverScannerLimit(t *testing.T) {
+	// bufio.Scanner's default MaxScanTokenSize is 64KB, and a single
+	// line past a Scanner's limit makes Scan return false for good,
+	// silently dropping every line read after it, panics included.
+	giantLine := strings.Repeat("x", 2*1024*1024) + "\n"
+	panicTxt := "panic: real panic after a giant line\n" +
+		"\n" +
+		"goroutine 1 [running]:\n" +
+		"main.main()\n" +
+		"\t/tmp/main.go:8 +0x20\n"
+
+	var mirror bytes.Buffer
+	result := DetectPanic(strings.NewReader(giantLine+panicTxt), 50*time.Millisecond, 0, &mirror)
+	if result != panicTxt {
+		t.Fatalf("bad result: %#v", result)
+	}
+
+	if mirror.String() != giantLine {
+		t.Fatalf("didn't mirror the giant line: got %d bytes", mirror.Len())
+	}
+}
+
+func TestDetectPanic_mirrorsNonPanicOutput(t *testing.T) {
+	raw := "line one\nline two\nline three\n"
+
+	var mirror bytes.Buffer
+	result := DetectPanic(strings.NewReader(raw), 50*time.Millisecond, 0, &mirror)
+	if result != "" {
+		t.Fatalf("shouldn't have confirmed a panic: %#v", result)
+	}
+
+	if mirror.String() != raw {
+		t.Fatalf("bad mirrored output: %#v", mirror.String())
+	}
+}
+
+func FuzzDetectPanic(f *testing.F) {
+	f.Add("just some ordinary log output\nanother line\n")
+	f.Add("panic: boom\n\ngoroutine 1 [running]:\nmain.main()\n\t/tmp/main.go:8 +0x20\n")
+	f.Add("fatal error: all goroutines are asleep - deadlock!\n\ngoroutine 1 [chan receive]:\nmain.main()\n\t/tmp/main.go:8 +0x20\n")
+	f.Add("panic: looks scary but isn't\njust some more output\nand more\n")
+	f.Add(strings.Repeat("a very long line of ordinary output that is not a panic\n", 500) +
+		"panic: a real panic buried under a pile of logs\n\ngoroutine 1 [running]:\nmain.main()\n\t/tmp/main.go:8 +0x20\n")
+	f.Add(strings.Repeat("x", 2*1024*1024) + "\n" +
+		"panic: a real panic after a giant single line\n\ngoroutine 1 [running]:\nmain.main()\n\t/tmp/main.go:8 +0x20\n")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			DetectPanic(strings.NewReader(s), time.Millisecond, 50, new(bytes.Buffer))
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("DetectPanic didn't terminate for input: %#v", s)
+		}
+	})
+}
@@ -0,0 +1,11 @@
+//go:build linux && !arm64
+// +build linux,!arm64
+
+package panicwrap
+
+import "syscall"
+
+// dup2 duplicates oldfd onto newfd, closing newfd first if it is open.
+func dup2(oldfd, newfd int) error {
+	return syscall.Dup2(oldfd, newfd)
+}
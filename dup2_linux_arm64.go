@@ -0,0 +1,13 @@
+//go:build linux && arm64
+// +build linux,arm64
+
+package panicwrap
+
+import "syscall"
+
+// dup2 duplicates oldfd onto newfd. syscall.Dup2 isn't implemented for
+// linux/arm64, so we fall back to Dup3, which with no flags set behaves
+// like dup2 for the distinct oldfd and newfd used here.
+func dup2(oldfd, newfd int) error {
+	return syscall.Dup3(oldfd, newfd, 0)
+}
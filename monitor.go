@@ -0,0 +1,58 @@
+package panicwrap
+
+import "os"
+
+// monitorCookieKey is set in the environment of the re-exec'd monitor
+// child so that it can tell itself apart from a normal invocation of the
+// wrapped program. It is used in place of WrapConfig's CookieKey (the
+// value is still WrapConfig's CookieValue) because Monitor, unlike Wrap,
+// never re-executes the program's real entrypoint: the monitor child
+// branches away before any of the caller's code runs.
+const monitorCookieKey = "c25a3eadf1fda75b95e1e92a1d2de0e4b7cdef2e"
+
+// BasicMonitor calls Monitor with the given handler function, using
+// defaults for everything else. See Monitor and WrapConfig for more
+// information on functionality and return values.
+func BasicMonitor(f HandlerFunc) (int, error) {
+	return Monitor(&WrapConfig{
+		Handler: f,
+	})
+}
+
+// monitorChild runs inside the re-exec'd monitor process. It reads the
+// original process's stderr from the pipe passed in as fd 3, mirroring
+// non-panic output through its own stderr (which has been wired up to
+// point at the original process's real stderr), and, once the original
+// process exits and the pipe closes, calls the handler if a panic was
+// confirmed.
+func monitorChild(c *WrapConfig) {
+	pipe := os.NewFile(3, "panicwrap-pipe")
+
+	panicCh := make(chan string)
+	go trackPanic(pipe, c.DetectDuration, panicCh)
+
+	panicTxt := <-panicCh
+	if panicTxt != "" {
+		dispatchPanic(c, panicTxt)
+	}
+}
+
+// dispatchPanic is the handling a confirmed panic gets once Wrap or
+// monitorChild has its text in hand: mirror it to stderr unless
+// HidePanic is set, call Handler, and call StructuredHandler with the
+// parsed PanicInfo if the text parses.
+func dispatchPanic(c *WrapConfig, panicTxt string) {
+	if !c.HidePanic {
+		os.Stderr.Write([]byte(panicTxt))
+	}
+
+	if c.Handler != nil {
+		c.Handler(panicTxt)
+	}
+
+	if c.StructuredHandler != nil {
+		if info, err := ParsePanic(panicTxt); err == nil {
+			c.StructuredHandler(info)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package panicwrap
+
+import (
+	"bytes"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var wrappedRe = regexp.MustCompile(`wrapped: (\d+)`)
+
+func TestMonitor_Output(t *testing.T) {
+	stderr := new(bytes.Buffer)
+	stdout := new(bytes.Buffer)
+
+	p := helperProcess("monitor-output")
+	p.Stdout = stdout
+	p.Stderr = stderr
+	if err := p.Run(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !strings.Contains(stdout.String(), "i am output") {
+		t.Fatalf("didn't forward: %#v", stdout.String())
+	}
+
+	if !strings.Contains(stderr.String(), "stderr out") {
+		t.Fatalf("didn't forward: %#v", stderr.String())
+	}
+}
+
+func TestMonitor_Wrap(t *testing.T) {
+	stdout := new(bytes.Buffer)
+
+	// Unlike Wrap, the process we run here is the one that actually
+	// panics: Monitor keeps it running in place and only the detached
+	// monitor child observes and reports the panic, so we expect this
+	// process to exit non-zero the way any unhandled panic would.
+	p := helperProcess("monitor-panic")
+	p.Stdout = stdout
+	if err := p.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// The child's real panic trace, and thus the length Handler reports,
+	// varies across Go toolchains, so just confirm Handler ran with a
+	// non-empty panic rather than pinning an exact byte count. Pulling
+	// the match out with a regexp also sidesteps whatever the test
+	// binary's own "--- FAIL: TestHelperProcess" diagnostics (from the
+	// real panic unwinding through go test itself) add around it.
+	m := wrappedRe.FindStringSubmatch(stdout.String())
+	if m == nil {
+		t.Fatalf("didn't wrap: %#v", stdout.String())
+	}
+
+	if n, err := strconv.Atoi(m[1]); err != nil || n == 0 {
+		t.Fatalf("wrapped with an empty panic: %#v", stdout.String())
+	}
+}
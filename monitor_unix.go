@@ -0,0 +1,107 @@
+//go:build !windows
+// +build !windows
+
+package panicwrap
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/mitchellh/osext"
+)
+
+// Monitor is an alternative to Wrap for programs that can't afford to pay
+// for a full re-exec of themselves, e.g. because they inherited privileged
+// file descriptors or have expensive startup. Instead of spawning a second
+// copy of the binary to run the program while the original process idles
+// and only watches stderr, Monitor keeps the original process running the
+// program in place and instead redirects its stderr to a small monitor
+// child, splicing a pipe in at the file descriptor level with dup2. The
+// monitor child runs the same panic-tracking loop as Wrap and invokes the
+// handler once the original process exits.
+//
+// Monitor returns -1 in the original process, once the monitor child is
+// wired up and the caller should continue with its normal startup. In the
+// monitor child itself Monitor never returns: it runs the monitor loop and
+// then calls os.Exit, so none of the caller's code runs there. A non-nil
+// error means the monitor child could not be started and the caller is
+// responsible for deciding how to proceed.
+//
+// Monitor relies on dup2, which isn't available on Windows; see
+// monitor_windows.go for the fallback used there.
+func Monitor(c *WrapConfig) (int, error) {
+	if c.Handler == nil && c.StructuredHandler == nil {
+		return -1, errors.New("Handler or StructuredHandler must be set")
+	}
+
+	if c.CookieValue == "" {
+		c.CookieValue = DEFAULT_COOKIE_VAL
+	}
+
+	if c.DetectDuration == 0 {
+		c.DetectDuration = 300 * time.Millisecond
+	}
+
+	// If we're the re-exec'd monitor child, run the monitor loop and
+	// never return to the caller.
+	if os.Getenv(monitorCookieKey) == c.CookieValue {
+		monitorChild(c)
+		os.Exit(0)
+	}
+
+	// Duplicate the real stderr aside so the monitor child has something
+	// to mirror output to once we steal fd 2 for ourselves below.
+	realStderrFd, err := syscall.Dup(int(os.Stderr.Fd()))
+	if err != nil {
+		return -1, err
+	}
+	realStderr := os.NewFile(uintptr(realStderrFd), "panicwrap-stderr")
+
+	stderr_r, stderr_w, err := os.Pipe()
+	if err != nil {
+		realStderr.Close()
+		return -1, err
+	}
+
+	// Splice the pipe in at fd 2 so every write this process makes to
+	// stderr, including the runtime's own panic output, flows to the
+	// monitor child instead of the terminal.
+	if err := dup2(int(stderr_w.Fd()), int(os.Stderr.Fd())); err != nil {
+		realStderr.Close()
+		stderr_r.Close()
+		stderr_w.Close()
+		return -1, err
+	}
+	stderr_w.Close()
+
+	exePath, err := osext.Executable()
+	if err != nil {
+		// fd 2 is spliced to the pipe at this point; put the real
+		// stderr back before giving up on it.
+		dup2(realStderrFd, int(os.Stderr.Fd()))
+		realStderr.Close()
+		stderr_r.Close()
+		return -1, err
+	}
+
+	cmd := exec.Command(exePath, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), monitorCookieKey+"="+c.CookieValue)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = realStderr
+	cmd.ExtraFiles = []*os.File{stderr_r}
+	if err := cmd.Start(); err != nil {
+		dup2(realStderrFd, int(os.Stderr.Fd()))
+		realStderr.Close()
+		stderr_r.Close()
+		return -1, err
+	}
+
+	realStderr.Close()
+	stderr_r.Close()
+
+	return -1, nil
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package panicwrap
+
+import "errors"
+
+// Monitor on Windows has no dup2 to splice a pipe in at the file
+// descriptor level, so it simply falls back to the re-exec flow used by
+// Wrap. See monitor_unix.go for the fd-splicing implementation used
+// everywhere else.
+func Monitor(c *WrapConfig) (int, error) {
+	if c.Handler == nil && c.StructuredHandler == nil {
+		return -1, errors.New("Handler or StructuredHandler must be set")
+	}
+
+	return Wrap(c)
+}
@@ -10,15 +10,14 @@
 package panicwrap
 
 import (
-	"bytes"
 	"errors"
 	"github.com/mitchellh/osext"
 	"io"
-	"math"
 	"os"
 	"os/exec"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 const (
@@ -42,6 +41,34 @@ type WrapConfig struct {
 	// wrap doesn't re-wrap itself.
 	CookieKey   string
 	CookieValue string
+
+	// DetectDuration is how long trackPanic, after a suspected panic
+	// header, waits for another line following the last one it buffered
+	// before giving up and treating it as a false positive. A real panic's stack trace can take a moment to finish
+	// printing, so this needs to be long enough to cover that, but short
+	// enough that ordinary output containing the string "panic:" isn't
+	// held up for long. Defaults to 300 milliseconds.
+	DetectDuration time.Duration
+
+	// HidePanic, if true, keeps a confirmed panic's text from being
+	// mirrored to stderr before Handler runs, giving Handler exclusive
+	// ownership of the report. This isn't recommended unless Handler is
+	// guaranteed to do something with the text itself, since otherwise
+	// the panic is effectively lost.
+	HidePanic bool
+
+	// ForwardSignals is the set of signals that are forwarded to the
+	// wrapped child process as they're received, so that the child can
+	// handle them or die from them instead of only the parent seeing
+	// them. Defaults to the common termination and user-defined signals
+	// on Unix, and to os.Interrupt on Windows.
+	ForwardSignals []os.Signal
+
+	// StructuredHandler, if set, is called with a parsed PanicInfo
+	// whenever a panic is detected, in addition to Handler. Either
+	// Handler or StructuredHandler (or both) must be set. If the panic
+	// text can't be parsed, StructuredHandler is simply not called.
+	StructuredHandler func(*PanicInfo)
 }
 
 // BasicWrap calls Wrap with the given handler function, using defaults
@@ -67,8 +94,8 @@ func BasicWrap(f HandlerFunc) (int, error) {
 // Once this is called, the given WrapConfig shouldn't be modified or used
 // any further.
 func Wrap(c *WrapConfig) (int, error) {
-	if c.Handler == nil {
-		return -1, errors.New("Handler must be set")
+	if c.Handler == nil && c.StructuredHandler == nil {
+		return -1, errors.New("Handler or StructuredHandler must be set")
 	}
 
 	if c.CookieKey == "" {
@@ -79,9 +106,17 @@ func Wrap(c *WrapConfig) (int, error) {
 		c.CookieValue = DEFAULT_COOKIE_VAL
 	}
 
-	// If the cookie key/value match our environment, then we are the
-	// child, so just exit now and tell the caller that we're the child
-	if os.Getenv(c.CookieKey) == c.CookieValue {
+	if c.DetectDuration == 0 {
+		c.DetectDuration = 300 * time.Millisecond
+	}
+
+	if len(c.ForwardSignals) == 0 {
+		c.ForwardSignals = defaultForwardSignals
+	}
+
+	// If we're already the wrapped child, just exit now and tell the
+	// caller that we're the child
+	if Wrapped(c) {
 		return -1, nil
 	}
 
@@ -104,7 +139,7 @@ func Wrap(c *WrapConfig) (int, error) {
 	}()
 
 	// Start the goroutine that will watch stderr for any panics
-	go trackPanic(stderr_r, panicCh)
+	go trackPanic(stderr_r, c.DetectDuration, panicCh)
 
 	// Build a subcommand to re-execute ourselves. We make sure to
 	// set the environmental variable to include our cookie. We also
@@ -119,17 +154,32 @@ func Wrap(c *WrapConfig) (int, error) {
 		return 1, err
 	}
 
-	// Listen to signals and capture them forever. We allow the child
-	// process to handle them in some way.
-	sigCh := make(chan os.Signal)
+	// Listen to signals and capture them forever. os.Interrupt is usually
+	// sent to the whole process group already, but that's not guaranteed
+	// (e.g. a supervisor signaling just the wrapper's pid, or the child
+	// detached into its own session), so we relay it to the child
+	// explicitly here rather than swallowing it; everything in
+	// ForwardSignals is relayed the same way so the child can handle it
+	// or die from it as it normally would.
+	sigCh := make(chan os.Signal, 1)
+	fwdSigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt)
+	signal.Notify(fwdSigCh, c.ForwardSignals...)
 	go func() {
 		defer signal.Stop(sigCh)
+		defer signal.Stop(fwdSigCh)
 		for {
 			select {
 			case <-doneCh:
 				return
-			case <-sigCh:
+			case s := <-fwdSigCh:
+				if cmd.Process != nil {
+					cmd.Process.Signal(s)
+				}
+			case s := <-sigCh:
+				if cmd.Process != nil {
+					cmd.Process.Signal(s)
+				}
 			}
 		}
 	}()
@@ -152,7 +202,7 @@ func Wrap(c *WrapConfig) (int, error) {
 		// Wait on the panic data
 		panicTxt := <-panicCh
 		if panicTxt != "" {
-			c.Handler(panicTxt)
+			dispatchPanic(c, panicTxt)
 		}
 
 		return exitStatus, nil
@@ -161,116 +211,33 @@ func Wrap(c *WrapConfig) (int, error) {
 	return 0, nil
 }
 
-func trackPanic(r io.Reader, result chan<- string) {
-	defer close(result)
-
-	panicHeader := []byte("panic:")
-
-	// Maintain a circular buffer of the data being read.
-	buf := make([]byte, 2048)
-	panicStart := -1
-	cursor := 0
-	readCursor := 0
-
-	readPanicLen := func() int {
-		if cursor < panicStart {
-			// The cursor has wrapped around the end.
-			return (len(buf) - panicStart) + cursor
-		} else {
-			return cursor - panicStart
-		}
+// Wrapped checks whether the current process is the wrapped child, i.e.
+// whether a call to Wrap with this configuration would immediately return
+// with exit status -1. It honors c.CookieKey/c.CookieValue the same way
+// Wrap does, defaulting to DEFAULT_COOKIE_KEY/DEFAULT_COOKIE_VAL when they
+// are empty.
+//
+// Wrapped is safe to call before Wrap (or instead of it) and doesn't
+// modify c, so it can be used to pick a different startup path in the
+// child versus the parent before committing to wrapping at all.
+func Wrapped(c *WrapConfig) bool {
+	cookieKey := c.CookieKey
+	if cookieKey == "" {
+		cookieKey = DEFAULT_COOKIE_KEY
 	}
 
-	readPanicBytes := func() []byte {
-		panicBytes := make([]byte, readPanicLen())
-		if cursor < panicStart {
-			copy(panicBytes, buf[panicStart:len(buf)])
-			copy(panicBytes[len(buf)-panicStart:], buf[0:cursor])
-		} else {
-			copy(panicBytes, buf[panicStart:cursor])
-		}
-
-		return panicBytes
+	cookieValue := c.CookieValue
+	if cookieValue == "" {
+		cookieValue = DEFAULT_COOKIE_VAL
 	}
 
-	for {
-		for panicStart < 0 && readCursor != cursor {
-			// We're not currently tracking a panic, so we determine if
-			// we have a panic by looking at the last handful of bytes.
-			readCursorEnd := cursor
-			if cursor < readCursor {
-				readCursorEnd = len(buf)
-			}
-
-			inspectBuf := buf[readCursor:readCursorEnd]
-			idx := bytes.Index(inspectBuf, panicHeader)
-			if idx >= 0 {
-				panicStart = readCursor + idx
-				readCursorEnd = panicStart
-			}
-
-			// Write out the buffer we read to stderr to mirror it
-			// through. If a panic started, we only write up to the
-			// start of the panic.
-			os.Stderr.Write(buf[readCursor:readCursorEnd])
-
-			// Move the read cursor
-			readCursor = readCursorEnd
-			if readCursor > len(buf) {
-				panic("read cursor past end of buffer")
-			} else if readCursor == len(buf) {
-				readCursor = 0
-			}
-		}
-
-		if panicStart >= 0 && readPanicLen() >= 512 {
-			// We're currently tracking a panic. If we've read at least
-			// a certain number of bytes of the panic, verify if it is
-			// a real panic. Otherwise, continue to just collect bytes.
-			panicBytes := readPanicBytes()
-
-			if !verifyPanic(panicBytes) {
-				// Push the read cursor by at least one so we don't
-				// infinite loop
-				os.Stderr.Write(buf[panicStart : panicStart+1])
-				readCursor += 1
-				panicStart = -1
-				continue
-			}
-
-			panicTxt := new(bytes.Buffer)
-			panicTxt.Write(panicBytes)
-			io.Copy(panicTxt, r)
-			result <- panicTxt.String()
-			return
-		}
-
-		// Read into the next portion of our buffer
-		cursorEnd := cursor + int(math.Min(1024, float64(len(buf)-cursor)))
-		n, err := r.Read(buf[cursor:cursorEnd])
-		if n <= 0 {
-			if err == nil {
-				continue
-			} else if err == io.EOF {
-				result <- string(readPanicBytes())
-				return
-			}
-
-			// TODO(mitchellh): handle errors?
-		}
-
-		cursor += n
-		if cursor > len(buf) {
-			panic("cursor past the end of the buffer")
-		}
-
-		if cursor == len(buf) {
-			// Wrap around our buffer if we reached the end
-			cursor = 0
-		}
-	}
+	return os.Getenv(cookieKey) == cookieValue
 }
 
-func verifyPanic(p []byte) bool {
-	return bytes.Index(p, []byte("goroutine ")) != -1
+// trackPanic monitors the given reader for a panic using DetectPanic. If
+// a panic is detected, it is outputted on the result channel. This will
+// close the channel once it is complete.
+func trackPanic(r io.Reader, dur time.Duration, result chan<- string) {
+	defer close(result)
+	result <- DetectPanic(r, dur, 0, os.Stderr)
 }
@@ -3,11 +3,12 @@ package panicwrap
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"os/signal"
 	"strings"
 	"testing"
+	"time"
 )
 
 func helperProcess(s ...string) *exec.Cmd {
@@ -83,13 +84,66 @@ func TestHelperProcess(*testing.T) {
 		}
 
 		if exitStatus < 0 {
-			c := make(chan os.Signal)
-			signal.Notify(c, os.Interrupt)
-			<-c
-			fmt.Fprintf(os.Stdout, "got sigint")
+			fmt.Fprintf(os.Stdout, "got signal: %s", waitForSignal())
 			exitStatus = 0
 		}
 
+		os.Exit(exitStatus)
+	case "wrapped":
+		config := &WrapConfig{
+			Handler: func(s string) {
+				fmt.Fprintf(os.Stdout, "wrapped: %d", len(s))
+				os.Exit(0)
+			},
+		}
+
+		// Branch startup based on Wrapped before ever calling Wrap, the
+		// way a real program choosing a different init path would.
+		if Wrapped(config) {
+			fmt.Fprintf(os.Stdout, "%v", true)
+			os.Exit(0)
+		}
+
+		exitStatus, err := Wrap(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "wrap error: %s", err)
+			os.Exit(1)
+		}
+
+		os.Exit(exitStatus)
+	case "monitor-output":
+		exitStatus, err := BasicMonitor(func(s string) {
+			fmt.Fprintf(os.Stdout, "wrapped: %d", len(s))
+			os.Exit(0)
+		})
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monitor error: %s", err)
+			os.Exit(1)
+		}
+
+		if exitStatus >= 0 {
+			os.Exit(exitStatus)
+		}
+
+		fmt.Fprint(os.Stdout, "i am output")
+		fmt.Fprint(os.Stderr, "stderr out")
+		os.Exit(0)
+	case "monitor-panic":
+		exitStatus, err := BasicMonitor(func(s string) {
+			fmt.Fprintf(os.Stdout, "wrapped: %d", len(s))
+			os.Exit(0)
+		})
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monitor error: %s", err)
+			os.Exit(1)
+		}
+
+		if exitStatus < 0 {
+			panic("uh oh")
+		}
+
 		os.Exit(exitStatus)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %q\n", cmd)
@@ -130,3 +184,113 @@ func TestPanicWrap_Wrap(t *testing.T) {
 		t.Fatalf("didn't wrap: %#v", stdout.String())
 	}
 }
+
+func TestWrapped(t *testing.T) {
+	stdout := new(bytes.Buffer)
+
+	p := helperProcess("wrapped")
+	p.Stdout = stdout
+	if err := p.Run(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !strings.Contains(stdout.String(), "true") {
+		t.Fatalf("didn't detect wrapped child: %#v", stdout.String())
+	}
+}
+
+// writeSlowly trickles data into w one chunk at a time, sleeping delay
+// between each so tests can exercise trackPanic's idle-timeout behavior
+// without depending on real process/panic timing.
+func writeSlowly(w io.Writer, delay time.Duration, chunks ...string) {
+	for _, chunk := range chunks {
+		w.Write([]byte(chunk))
+		time.Sleep(delay)
+	}
+}
+
+func TestTrackPanic_real(t *testing.T) {
+	r, w := io.Pipe()
+	result := make(chan string)
+	go trackPanic(r, 50*time.Millisecond, result)
+
+	go func() {
+		writeSlowly(w, 10*time.Millisecond,
+			"ordinary output\n",
+			"panic: oh no\n\n",
+			"goroutine 1 [running]:\n",
+			"main.main()\n",
+			"\t/tmp/main.go:5 +0x1\n",
+		)
+		w.Close()
+	}()
+
+	txt := <-result
+	if !strings.Contains(txt, "panic: oh no") || !strings.Contains(txt, "goroutine 1") {
+		t.Fatalf("didn't capture panic: %#v", txt)
+	}
+}
+
+func TestTrackPanic_falsePositive(t *testing.T) {
+	stderr := hookStderr(t)
+
+	r, w := io.Pipe()
+	result := make(chan string)
+	go trackPanic(r, 20*time.Millisecond, result)
+
+	go func() {
+		writeSlowly(w, 50*time.Millisecond,
+			"panic: not actually a panic, just a log line\n",
+		)
+		w.Close()
+	}()
+
+	txt := <-result
+	if txt != "" {
+		t.Fatalf("shouldn't have detected a panic: %#v", txt)
+	}
+
+	if !strings.Contains(stderr(), "panic: not actually a panic") {
+		t.Fatalf("false positive wasn't flushed to stderr")
+	}
+}
+
+// hookStderr temporarily redirects the package-level os.Stderr used by
+// trackPanic to a pipe, returning a function that closes the redirect and
+// returns everything written to it.
+func hookStderr(t *testing.T) func() string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	old := os.Stderr
+	os.Stderr = w
+
+	captured := make(chan string)
+	go func() {
+		buf := new(bytes.Buffer)
+		io.Copy(buf, r)
+		captured <- buf.String()
+	}()
+
+	done := false
+	return func() string {
+		if !done {
+			done = true
+			os.Stderr = old
+			w.Close()
+		}
+
+		// Give the redirected writes a moment to land before we stop
+		// waiting for them.
+		select {
+		case s := <-captured:
+			return s
+		case <-time.After(200 * time.Millisecond):
+			return ""
+		}
+	}
+}
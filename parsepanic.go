@@ -0,0 +1,149 @@
+package panicwrap
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PanicInfo is a structured breakdown of a panic's text, produced by
+// ParsePanic.
+type PanicInfo struct {
+	// Reason is the text that followed "panic:". If the panic text
+	// contains more than one "panic:" header (a recovered panic
+	// followed by a re-panic), Reason is the last one, since that's the
+	// one that actually crashed the program.
+	Reason string
+
+	// Recovered is true if the panic text indicates an earlier panic
+	// was recovered before this one was raised.
+	Recovered bool
+
+	// Goroutines holds every goroutine stack trace found in the panic
+	// text, in the order the runtime printed them.
+	Goroutines []Goroutine
+}
+
+// Goroutine is a single goroutine's stack trace within a panic.
+type Goroutine struct {
+	// ID is the goroutine's numeric ID, as printed by the runtime.
+	ID int
+
+	// State is the goroutine's state at the time of the panic, e.g.
+	// "running" or "chan receive".
+	State string
+
+	// Stack is the goroutine's call stack, innermost frame first.
+	Stack []Frame
+}
+
+// Frame is a single call frame within a goroutine's stack trace.
+type Frame struct {
+	// Func is the function the frame is executing in, e.g.
+	// "main.(*Foo).Bar".
+	Func string
+
+	// Args are the raw argument values the runtime printed for the
+	// call, e.g. []string{"0x1", "0x2"}. Nil if the frame had none.
+	Args []string
+
+	// File and Line are the source location the frame was at.
+	File string
+	Line int
+
+	// PC is the program counter offset the runtime printed, i.e. the
+	// 0x4a in "+0x4a".
+	PC uintptr
+}
+
+var (
+	panicHeaderRe   = regexp.MustCompile(`^(?:panic|fatal error|runtime error): (.*)$`)
+	goroutineHeadRe = regexp.MustCompile(`^goroutine (\d+) \[(.+)\]:$`)
+	frameLineRe     = regexp.MustCompile(`^(.+):(\d+)(?: \+0x([0-9a-fA-F]+))?$`)
+)
+
+// ParsePanic parses the raw text of a captured panic, as passed to a
+// HandlerFunc, into a structured PanicInfo. It understands the format
+// runtime.Stack produces: a "panic: X" header, or the "fatal error: X" /
+// "runtime error: X" headers DetectPanic also treats as confirmed panics
+// (possibly repeated, for a recovered panic followed by a re-panic), one
+// or more "goroutine N [state]:" sections, and the alternating
+// function-line / "\tfile:line +0xNN" pairs that make up each goroutine's
+// stack.
+//
+// ParsePanic returns an error if raw doesn't start with a panic header at
+// all. Otherwise it does its best with whatever goroutine sections are
+// present, so a truncated or unusual trace still yields a partial result.
+func ParsePanic(raw string) (*PanicInfo, error) {
+	lines := strings.Split(raw, "\n")
+	i := 0
+
+	info := &PanicInfo{}
+	foundHeader := false
+	for i < len(lines) {
+		line := strings.TrimLeft(lines[i], "\t")
+		m := panicHeaderRe.FindStringSubmatch(line)
+		if m == nil {
+			break
+		}
+
+		foundHeader = true
+		reason := m[1]
+		if strings.HasSuffix(reason, "[recovered]") {
+			info.Recovered = true
+			reason = strings.TrimSpace(strings.TrimSuffix(reason, "[recovered]"))
+		}
+		info.Reason = reason
+		i++
+	}
+
+	if !foundHeader {
+		return nil, errors.New("panicwrap: no panic header found")
+	}
+
+	for i < len(lines) {
+		m := goroutineHeadRe.FindStringSubmatch(lines[i])
+		if m == nil {
+			i++
+			continue
+		}
+
+		id, _ := strconv.Atoi(m[1])
+		g := Goroutine{ID: id, State: m[2]}
+		i++
+
+		for i < len(lines) && lines[i] != "" && goroutineHeadRe.FindStringSubmatch(lines[i]) == nil {
+			funcLine := lines[i]
+			i++
+			if i >= len(lines) {
+				break
+			}
+			frameLine := strings.TrimLeft(lines[i], "\t")
+			i++
+
+			frame := Frame{Func: funcLine}
+			if idx := strings.LastIndex(funcLine, "("); idx >= 0 && strings.HasSuffix(funcLine, ")") {
+				frame.Func = funcLine[:idx]
+				if args := funcLine[idx+1 : len(funcLine)-1]; args != "" {
+					frame.Args = strings.Split(args, ", ")
+				}
+			}
+
+			if fm := frameLineRe.FindStringSubmatch(frameLine); fm != nil {
+				frame.File = fm[1]
+				frame.Line, _ = strconv.Atoi(fm[2])
+				if fm[3] != "" {
+					pc, _ := strconv.ParseUint(fm[3], 16, 64)
+					frame.PC = uintptr(pc)
+				}
+			}
+
+			g.Stack = append(g.Stack, frame)
+		}
+
+		info.Goroutines = append(info.Goroutines, g)
+	}
+
+	return info, nil
+}
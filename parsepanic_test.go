@@ -0,0 +1,135 @@
+package panicwrap
+
+import "testing"
+
+func TestParsePanic_single(t *testing.T) {
+	raw := `panic: something went wrong
+
+goroutine 1 [running]:
+main.bar(0x1, 0x2)
+	/tmp/main.go:12 +0x45
+main.main()
+	/tmp/main.go:8 +0x20
+`
+
+	info, err := ParsePanic(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if info.Reason != "something went wrong" {
+		t.Fatalf("bad reason: %#v", info.Reason)
+	}
+
+	if info.Recovered {
+		t.Fatalf("shouldn't be marked recovered")
+	}
+
+	if len(info.Goroutines) != 1 {
+		t.Fatalf("bad goroutine count: %d", len(info.Goroutines))
+	}
+
+	g := info.Goroutines[0]
+	if g.ID != 1 || g.State != "running" {
+		t.Fatalf("bad goroutine header: %+v", g)
+	}
+
+	if len(g.Stack) != 2 {
+		t.Fatalf("bad frame count: %d", len(g.Stack))
+	}
+
+	f := g.Stack[0]
+	if f.Func != "main.bar" || len(f.Args) != 2 || f.Args[0] != "0x1" {
+		t.Fatalf("bad frame: %+v", f)
+	}
+
+	if f.File != "/tmp/main.go" || f.Line != 12 || f.PC != 0x45 {
+		t.Fatalf("bad frame location: %+v", f)
+	}
+}
+
+func TestParsePanic_manyGoroutines(t *testing.T) {
+	raw := `panic: boom
+
+goroutine 1 [running]:
+main.main()
+	/tmp/main.go:8 +0x20
+
+goroutine 5 [chan receive]:
+main.worker()
+	/tmp/main.go:20 +0x10
+created by main.main
+	/tmp/main.go:9 +0x4
+`
+
+	info, err := ParsePanic(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(info.Goroutines) != 2 {
+		t.Fatalf("bad goroutine count: %d", len(info.Goroutines))
+	}
+
+	if info.Goroutines[1].ID != 5 || info.Goroutines[1].State != "chan receive" {
+		t.Fatalf("bad second goroutine: %+v", info.Goroutines[1])
+	}
+
+	if len(info.Goroutines[1].Stack) != 2 {
+		t.Fatalf("bad second goroutine frame count: %d", len(info.Goroutines[1].Stack))
+	}
+
+	created := info.Goroutines[1].Stack[1]
+	if created.Func != "created by main.main" || created.Line != 9 {
+		t.Fatalf("bad created-by frame: %+v", created)
+	}
+}
+
+func TestParsePanic_recovered(t *testing.T) {
+	raw := "panic: original error [recovered]\n" +
+		"\tpanic: re-panic error\n" +
+		"\n" +
+		"goroutine 1 [running]:\n" +
+		"main.main()\n" +
+		"\t/tmp/main.go:8 +0x20\n"
+
+	info, err := ParsePanic(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !info.Recovered {
+		t.Fatalf("should be marked recovered")
+	}
+
+	if info.Reason != "re-panic error" {
+		t.Fatalf("bad reason: %#v", info.Reason)
+	}
+}
+
+func TestParsePanic_fatalError(t *testing.T) {
+	raw := "fatal error: all goroutines are asleep - deadlock!\n" +
+		"\n" +
+		"goroutine 1 [chan receive]:\n" +
+		"main.main()\n" +
+		"\t/tmp/main.go:8 +0x20\n"
+
+	info, err := ParsePanic(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if info.Reason != "all goroutines are asleep - deadlock!" {
+		t.Fatalf("bad reason: %#v", info.Reason)
+	}
+
+	if len(info.Goroutines) != 1 || info.Goroutines[0].ID != 1 {
+		t.Fatalf("bad goroutines: %+v", info.Goroutines)
+	}
+}
+
+func TestParsePanic_noHeader(t *testing.T) {
+	if _, err := ParsePanic("just some ordinary output\n"); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+// +build !windows
+
+package panicwrap
+
+import (
+	"bytes"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPanicWrap_SignalForward(t *testing.T) {
+	cases := []struct {
+		signal syscall.Signal
+		expect string
+	}{
+		{syscall.SIGTERM, "terminated"},
+		{syscall.SIGUSR1, "user defined signal 1"},
+		{syscall.SIGINT, "interrupt"},
+	}
+
+	for _, tc := range cases {
+		stdout := new(bytes.Buffer)
+
+		p := helperProcess("signal")
+		p.Stdout = stdout
+		if err := p.Start(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		// Give the wrapped child a moment to start and register its
+		// own signal handler before we deliver the signal to the
+		// parent that's supposed to forward it.
+		time.Sleep(200 * time.Millisecond)
+
+		if err := p.Process.Signal(tc.signal); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if err := p.Wait(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if !strings.Contains(stdout.String(), "got signal: "+tc.expect) {
+			t.Fatalf("%s wasn't forwarded to the child: %#v", tc.signal, stdout.String())
+		}
+	}
+}
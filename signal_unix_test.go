@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package panicwrap
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// waitForSignal blocks until the helper process receives one of the
+// signals panicwrap is expected to forward to the child (os.Interrupt,
+// SIGTERM, SIGUSR1), and returns whichever arrived.
+func waitForSignal() os.Signal {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1)
+	return <-c
+}
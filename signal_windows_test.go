@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package panicwrap
+
+import (
+	"os"
+	"os/signal"
+)
+
+// waitForSignal blocks until the helper process receives os.Interrupt,
+// the only signal Windows gives meaningful support for.
+func waitForSignal() os.Signal {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	return <-c
+}
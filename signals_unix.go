@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package panicwrap
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultForwardSignals is the set of signals forwarded to the wrapped
+// child when WrapConfig.ForwardSignals is left empty: the common
+// termination signals, plus the user-defined ones that are only ever
+// delivered to the parent and so need to be relayed by hand.
+var defaultForwardSignals = []os.Signal{
+	syscall.SIGTERM,
+	syscall.SIGHUP,
+	syscall.SIGUSR1,
+	syscall.SIGUSR2,
+	syscall.SIGQUIT,
+}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package panicwrap
+
+import "os"
+
+// defaultForwardSignals is the set of signals forwarded to the wrapped
+// child when WrapConfig.ForwardSignals is left empty. Windows only has
+// meaningful support for os.Interrupt.
+var defaultForwardSignals = []os.Signal{os.Interrupt}